@@ -0,0 +1,82 @@
+// Command taskq provides small operational helpers for inspecting taskq
+// queues without having to write a one-off script for each one.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/iron-io/iron_go3/mq"
+
+	"github.com/vickxxx/taskq/v3"
+	"github.com/vickxxx/taskq/v3/inspector"
+	"github.com/vickxxx/taskq/v3/ironmq"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "peek":
+		err = runPeek(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "taskq:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: taskq peek [-n 10] <queue>")
+}
+
+// runPeek implements `taskq peek [-n 10] <queue>`: it connects to the named
+// IronMQ queue and prints up to n pending messages without reserving them.
+// The registered/unregistered status reflects taskq.Tasks in *this* process
+// only — this stock binary doesn't import any application task packages, so
+// everything prints unregistered unless it's built as part of (or alongside
+// an import of) the application whose tasks it's meant to inspect.
+func runPeek(args []string) error {
+	fs := flag.NewFlagSet("peek", flag.ExitOnError)
+	n := fs.Int("n", 10, "number of messages to peek")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+	queueName := fs.Arg(0)
+
+	q := ironmq.NewQueue(mq.New(queueName), &taskq.QueueOptions{Name: queueName})
+	insp := inspector.New(q)
+
+	msgs, err := insp.Peek(context.Background(), queueName, *n)
+	if err != nil {
+		return fmt.Errorf("peek %q: %w", queueName, err)
+	}
+
+	for _, msg := range msgs {
+		if msg.Err != nil {
+			fmt.Printf("%s\terror=%s\n", msg.ID, msg.Err)
+			continue
+		}
+
+		status := "unregistered"
+		if taskq.Tasks.Get(msg.TaskName) != nil {
+			status = "registered"
+		}
+		fmt.Printf("%s\ttask=%s (%s)\targs=%v\n", msg.ID, msg.TaskName, status, msg.Args)
+	}
+
+	return nil
+}
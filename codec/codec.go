@@ -0,0 +1,30 @@
+// Package codec defines the wire format used by taskq queues to turn a
+// *taskq.Message into bytes and back.
+package codec
+
+import (
+	"github.com/vickxxx/taskq/v3"
+)
+
+// Codec marshals and unmarshals a taskq.Message. Queues use it to serialize
+// messages before handing them to the underlying transport (IronMQ, SQS,
+// Redis, ...) and to deserialize them on the way back out.
+type Codec interface {
+	Marshal(msg *taskq.Message) ([]byte, error)
+	Unmarshal(b []byte, msg *taskq.Message) error
+}
+
+// Default is the codec used by QueueOptions when Codec is not set. It
+// preserves the historical wire format produced by Message.MarshalBinary, so
+// existing queues keep working unchanged.
+var Default Codec = binaryCodec{}
+
+type binaryCodec struct{}
+
+func (binaryCodec) Marshal(msg *taskq.Message) ([]byte, error) {
+	return msg.MarshalBinary()
+}
+
+func (binaryCodec) Unmarshal(b []byte, msg *taskq.Message) error {
+	return msg.UnmarshalBinary(b)
+}
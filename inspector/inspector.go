@@ -0,0 +1,117 @@
+// Package inspector provides a read-only view over registered queues, for
+// building dashboards and CLI tools (see cmd/taskq) without each backend
+// needing custom code.
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vickxxx/taskq/v3"
+	"github.com/vickxxx/taskq/v3/ironmq"
+)
+
+// CompletedTask describes a task whose handler has finished and whose
+// result was stored under QueueOptions.Storage (see TaskOptions.Retention).
+// The stored result may since have expired; CompletedTasks does not check.
+type CompletedTask struct {
+	QueueName   string
+	MessageID   string
+	CompletedAt time.Time
+}
+
+// Inspector aggregates Len, Peek and archived-queue listing across a set of
+// registered queues. Len, Peek and Archived only rely on the taskq.Queue
+// interface, so they work the same way regardless of backend; CompletedTasks
+// only covers backends that retain results (see completedTaskLister).
+type Inspector struct {
+	queues map[string]taskq.Queue
+}
+
+// New returns an Inspector over the given queues, indexed by Name.
+func New(queues ...taskq.Queue) *Inspector {
+	insp := &Inspector{queues: make(map[string]taskq.Queue, len(queues))}
+	for _, q := range queues {
+		insp.queues[q.Name()] = q
+	}
+	return insp
+}
+
+// Queue returns the registered queue with the given name, or an error if it
+// wasn't registered with New.
+func (insp *Inspector) Queue(name string) (taskq.Queue, error) {
+	q, ok := insp.queues[name]
+	if !ok {
+		return nil, fmt.Errorf("inspector: queue %q is not registered", name)
+	}
+	return q, nil
+}
+
+// Len returns the number of messages in the named queue.
+func (insp *Inspector) Len(name string) (int, error) {
+	q, err := insp.Queue(name)
+	if err != nil {
+		return 0, err
+	}
+	return q.Len()
+}
+
+// Peek returns up to n messages from the named queue without reserving
+// them.
+func (insp *Inspector) Peek(ctx context.Context, name string, n int) ([]taskq.Message, error) {
+	q, err := insp.Queue(name)
+	if err != nil {
+		return nil, err
+	}
+	return q.Peek(ctx, n)
+}
+
+// Archived returns up to n messages from the named queue's
+// QueueOptions.ArchiveQueue without reserving them. It returns an error if
+// the queue has no ArchiveQueue configured.
+func (insp *Inspector) Archived(ctx context.Context, name string, n int) ([]taskq.Message, error) {
+	q, err := insp.Queue(name)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := q.Options().ArchiveQueue
+	if archive == nil {
+		return nil, fmt.Errorf("inspector: queue %q has no ArchiveQueue configured", name)
+	}
+	return archive.Peek(ctx, n)
+}
+
+// completedTaskLister is implemented by queue backends that retain
+// completed task results; currently only ironmq.Queue does. Other backends
+// simply don't contribute to CompletedTasks until they grow the same
+// retention support.
+type completedTaskLister interface {
+	Name() string
+	CompletedTasks() []ironmq.CompletedTask
+}
+
+// CompletedTasks returns the completed tasks retained by every registered
+// queue that supports result retention, oldest first.
+func (insp *Inspector) CompletedTasks() []CompletedTask {
+	var tasks []CompletedTask
+	for _, q := range insp.queues {
+		lister, ok := q.(completedTaskLister)
+		if !ok {
+			continue
+		}
+		for _, t := range lister.CompletedTasks() {
+			tasks = append(tasks, CompletedTask{
+				QueueName:   lister.Name(),
+				MessageID:   t.ID,
+				CompletedAt: t.CompletedAt,
+			})
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].CompletedAt.Before(tasks[j].CompletedAt)
+	})
+	return tasks
+}
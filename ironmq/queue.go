@@ -2,19 +2,23 @@ package ironmq
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/iron-io/iron_go3/api"
 	"github.com/iron-io/iron_go3/mq"
 
 	"github.com/vickxxx/taskq/v3"
+	"github.com/vickxxx/taskq/v3/codec"
 	"github.com/vickxxx/taskq/v3/internal"
 	"github.com/vickxxx/taskq/v3/internal/base"
 	"github.com/vickxxx/taskq/v3/internal/msgutil"
 	"github.com/vickxxx/taskq/v3/memqueue"
+	"github.com/vickxxx/taskq/v3/persistqueue"
 )
 
 type Queue struct {
@@ -22,16 +26,31 @@ type Queue struct {
 
 	q mq.Queue
 
-	addQueue *memqueue.Queue
+	addQueue taskq.Queue
 	addTask  *taskq.Task
 
-	delQueue   *memqueue.Queue
+	delQueue   taskq.Queue
 	delTask    *taskq.Task
 	delBatcher *base.Batcher
 
-	consumer *taskq.Consumer
+	consumer  *taskq.Consumer
+	recoverer *Recoverer
+
+	completedMu sync.Mutex
+	completed   []CompletedTask
+}
+
+// CompletedTask records that a message finished processing and had its
+// result retained under QueueOptions.Storage.
+type CompletedTask struct {
+	ID          string
+	CompletedAt time.Time
 }
 
+// maxCompletedTasks bounds the in-memory CompletedTasks history so it can't
+// grow without limit on a long-running consumer.
+const maxCompletedTasks = 1000
+
 var _ taskq.Queue = (*Queue)(nil)
 
 func NewQueue(mqueue mq.Queue, opt *taskq.QueueOptions) *Queue {
@@ -53,11 +72,11 @@ func NewQueue(mqueue mq.Queue, opt *taskq.QueueOptions) *Queue {
 
 func (q *Queue) initAddQueue() {
 	queueName := "ironmq:" + q.opt.Name + ":add"
-	q.addQueue = memqueue.NewQueue(&taskq.QueueOptions{
+	q.addQueue = q.maybePersist(memqueue.NewQueue(&taskq.QueueOptions{
 		Name:       queueName,
 		BufferSize: 100,
 		Redis:      q.opt.Redis,
-	})
+	}))
 	q.addTask = taskq.RegisterTask(&taskq.TaskOptions{
 		Name:            queueName + ":add-mesage",
 		Handler:         taskq.HandlerFunc(q.add),
@@ -69,11 +88,11 @@ func (q *Queue) initAddQueue() {
 
 func (q *Queue) initDelQueue() {
 	queueName := "ironmq:" + q.opt.Name + ":delete"
-	q.delQueue = memqueue.NewQueue(&taskq.QueueOptions{
+	q.delQueue = q.maybePersist(memqueue.NewQueue(&taskq.QueueOptions{
 		Name:       queueName,
 		BufferSize: 100,
 		Redis:      q.opt.Redis,
-	})
+	}))
 	q.delTask = taskq.RegisterTask(&taskq.TaskOptions{
 		Name:       queueName + ":delete-message",
 		Handler:    taskq.HandlerFunc(q.delBatcherAdd),
@@ -86,6 +105,24 @@ func (q *Queue) initDelQueue() {
 	})
 }
 
+// maybePersist wraps inner in a persistqueue.Queue when QueueOptions.PersistDir
+// is set, so messages buffered in it survive a process crash between Add and
+// the background flush to IronMQ. Replay failures are logged and the
+// unwrapped queue is used instead, rather than failing NewQueue outright.
+func (q *Queue) maybePersist(inner taskq.Queue) taskq.Queue {
+	if q.opt.PersistDir == "" {
+		return inner
+	}
+
+	persisted, err := persistqueue.New(inner, &persistqueue.Options{Dir: q.opt.PersistDir})
+	if err != nil {
+		internal.Logger.Printf("ironmq: persistqueue.New(%q) failed, continuing without persistence: %s",
+			q.opt.PersistDir, err)
+		return inner
+	}
+	return persisted
+}
+
 func (q *Queue) Name() string {
 	return q.q.Name
 }
@@ -98,9 +135,22 @@ func (q *Queue) Options() *taskq.QueueOptions {
 	return q.opt
 }
 
+// codec returns the codec used to marshal/unmarshal messages, falling back
+// to the default binary format when QueueOptions.Codec is not set.
+func (q *Queue) codec() codec.Codec {
+	if q.opt.Codec != nil {
+		return q.opt.Codec
+	}
+	return codec.Default
+}
+
+// Consumer returns the queue's Consumer, creating it (and starting its
+// Recoverer) on first call.
 func (q *Queue) Consumer() *taskq.Consumer {
 	if q.consumer == nil {
 		q.consumer = taskq.NewConsumer(q)
+		q.recoverer = NewRecoverer(q, nil)
+		go q.recoverer.Run()
 	}
 	return q.consumer
 }
@@ -155,6 +205,28 @@ func (q *Queue) ReserveN(
 		return nil, err
 	}
 
+	return q.decodeMessages(mqMsgs), nil
+}
+
+// Peek returns up to n messages from the queue without reserving them, for
+// inspection by dashboards/CLI tools. See taskq.Queue.
+func (q *Queue) Peek(ctx context.Context, n int) ([]taskq.Message, error) {
+	if n > 100 {
+		n = 100
+	}
+
+	mqMsgs, err := q.q.PeekN(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.decodeMessages(mqMsgs), nil
+}
+
+// decodeMessages decodes each mq.Message body into a taskq.Message using the
+// queue's codec, recording any decode failure on Message.Err rather than
+// discarding it. Shared by ReserveN and Peek.
+func (q *Queue) decodeMessages(mqMsgs []mq.Message) []taskq.Message {
 	msgs := make([]taskq.Message, len(mqMsgs))
 	for i, mqMsg := range mqMsgs {
 		msg := &msgs[i]
@@ -162,11 +234,8 @@ func (q *Queue) ReserveN(
 		b, err := internal.DecodeString(mqMsg.Body)
 		if err != nil {
 			msg.Err = err
-		} else {
-			err = msg.UnmarshalBinary(b)
-			if err != nil {
-				msg.Err = err
-			}
+		} else if err := q.codec().Unmarshal(b, msg); err != nil {
+			msg.Err = err
 		}
 
 		msg.ID = mqMsg.Id
@@ -174,7 +243,7 @@ func (q *Queue) ReserveN(
 		msg.ReservedCount = mqMsg.ReservedCount
 	}
 
-	return msgs, nil
+	return msgs
 }
 
 func (q *Queue) Release(msg *taskq.Message) error {
@@ -183,18 +252,38 @@ func (q *Queue) Release(msg *taskq.Message) error {
 	})
 }
 
-// Delete deletes the message from the queue.
+// Delete deletes the message from the queue. Consumer calls Delete once the
+// message's handler has returned successfully, so this is also the point at
+// which a retained result (see storeResult) is persisted.
 func (q *Queue) Delete(msg *taskq.Message) error {
 	err := retry(func() error {
 		return q.q.DeleteMessage(msg.ID, msg.ReservationID)
 	})
-	if err == nil {
-		return nil
+	if err != nil {
+		if v, ok := err.(api.HTTPResponseError); ok && v.StatusCode() == 404 {
+			return nil
+		}
+		return err
 	}
-	if v, ok := err.(api.HTTPResponseError); ok && v.StatusCode() == 404 {
-		return nil
+
+	q.storeResult(msg)
+	return nil
+}
+
+// Archive moves msg to QueueOptions.ArchiveQueue, if one is configured, and
+// deletes it from q. It is used by Recoverer to dead-letter messages whose
+// RetryLimit has been exhausted, so they can be inspected or re-enqueued
+// later instead of being lost.
+func (q *Queue) Archive(msg *taskq.Message) error {
+	if q.opt.ArchiveQueue != nil {
+		archived := *msg
+		archived.ID = ""
+		archived.ReservationID = ""
+		if err := q.opt.ArchiveQueue.Add(&archived); err != nil {
+			return err
+		}
 	}
-	return err
+	return q.Delete(msg)
 }
 
 // Purge deletes all messages from the queue using IronMQ API.
@@ -212,6 +301,9 @@ func (q *Queue) CloseTimeout(timeout time.Duration) error {
 	if q.consumer != nil {
 		_ = q.consumer.StopTimeout(timeout)
 	}
+	if q.recoverer != nil {
+		q.recoverer.Stop()
+	}
 
 	firstErr := q.delBatcher.Close()
 
@@ -229,7 +321,7 @@ func (q *Queue) add(msg *taskq.Message) error {
 		return err
 	}
 
-	b, err := msg.MarshalBinary()
+	b, err := q.codec().Marshal(msg)
 	if err != nil {
 		return err
 	}
@@ -279,6 +371,89 @@ func (q *Queue) deleteBatch(msgs []*taskq.Message) error {
 	return nil
 }
 
+// queueResult is the payload persisted in QueueOptions.Storage for a
+// completed task, keyed by its message ID.
+type queueResult struct {
+	Result      []byte    `json:"result"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// storeResult persists the handler's result for msg under QueueOptions.Storage
+// when the message's task was registered with TaskOptions.Retention, so it
+// can later be looked up with Queue.Result. msg must be the message Consumer
+// handed to the task's handler, not a wrapped add/delete-buffer envelope,
+// since that's the only copy whose ResultWriter was actually written to.
+func (q *Queue) storeResult(msg *taskq.Message) {
+	task := taskq.Tasks.Get(msg.TaskName)
+	if task == nil || task.Options().Retention <= 0 {
+		return
+	}
+
+	rw := msg.ResultWriter()
+	if rw == nil {
+		return
+	}
+	result := rw.Bytes()
+	if len(result) == 0 {
+		return
+	}
+
+	b, err := json.Marshal(queueResult{
+		Result:      result,
+		CompletedAt: time.Now(),
+	})
+	if err != nil {
+		internal.Logger.Printf("ironmq: marshaling result for %s failed: %s", msg.ID, err)
+		return
+	}
+
+	err = q.opt.Storage.Set(msg.Ctx, q.resultKey(msg.ID), b, task.Options().Retention)
+	if err != nil {
+		internal.Logger.Printf("ironmq: storing result for %s failed: %s", msg.ID, err)
+		return
+	}
+
+	q.completedMu.Lock()
+	q.completed = append(q.completed, CompletedTask{ID: msg.ID, CompletedAt: time.Now()})
+	if len(q.completed) > maxCompletedTasks {
+		q.completed = q.completed[len(q.completed)-maxCompletedTasks:]
+	}
+	q.completedMu.Unlock()
+}
+
+// CompletedTasks returns the tasks this queue has completed and retained a
+// result for, oldest first. Used by inspector.Inspector to enumerate
+// completed tasks across queues.
+func (q *Queue) CompletedTasks() []CompletedTask {
+	q.completedMu.Lock()
+	defer q.completedMu.Unlock()
+	tasks := make([]CompletedTask, len(q.completed))
+	copy(tasks, q.completed)
+	return tasks
+}
+
+// Result returns the result and completion time previously stored for the
+// message with the given ID by storeResult. It returns an error if the
+// message's task was not registered with TaskOptions.Retention, or its
+// result has expired or was never stored.
+func (q *Queue) Result(ctx context.Context, msgID string) ([]byte, time.Time, error) {
+	b, err := q.opt.Storage.Get(ctx, q.resultKey(msgID))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var res queueResult
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return res.Result, res.CompletedAt, nil
+}
+
+func (q *Queue) resultKey(msgID string) string {
+	return "ironmq:" + q.opt.Name + ":result:" + msgID
+}
+
 func (q *Queue) shouldBatchDelete(batch []*taskq.Message, msg *taskq.Message) bool {
 	const messagesLimit = 10
 	return len(batch)+1 < messagesLimit
@@ -0,0 +1,150 @@
+package ironmq
+
+import (
+	"time"
+
+	"github.com/iron-io/iron_go3/api"
+
+	"github.com/vickxxx/taskq/v3"
+	"github.com/vickxxx/taskq/v3/internal"
+)
+
+// RecovererOptions configures a Recoverer.
+type RecovererOptions struct {
+	// CheckInterval is how often the recoverer polls the queue for messages
+	// to recover. Default is 30 seconds.
+	CheckInterval time.Duration
+
+	// BatchSize bounds how many messages the recoverer reserves per check.
+	// Default is 10.
+	BatchSize int
+
+	// MaxReserveCount bounds how many times a message may be reserved
+	// before a task without a registered RetryLimit is considered stuck.
+	// Default is 10.
+	MaxReserveCount int
+}
+
+func (opt *RecovererOptions) init() {
+	if opt.CheckInterval == 0 {
+		opt.CheckInterval = 30 * time.Second
+	}
+	if opt.BatchSize == 0 {
+		opt.BatchSize = 10
+	}
+	if opt.MaxReserveCount == 0 {
+		opt.MaxReserveCount = 10
+	}
+}
+
+// Recoverer periodically reserves a small batch of whatever IronMQ currently
+// has available for q and re-queues it with backoff, or moves it to
+// QueueOptions.ArchiveQueue once the task's RetryLimit is exhausted. It
+// exists to dead-letter messages whose handler keeps crashing or losing its
+// worker before Delete, which would otherwise make IronMQ redeliver them
+// forever.
+//
+// It reserves through the same LongPoll call ReserveN uses rather than
+// scanning with PeekN: IronMQ itself refuses to hand out a message under a
+// live, unexpired reservation, so whatever the recoverer gets back is
+// provably not being actively worked on, never something it had to guess
+// about or forcibly wrest away from a worker that's still mid-handler.
+//
+// IronMQ's client API doesn't expose how close to expiry a reservation is,
+// only ReservedCount once it lapses and the message becomes reservable
+// again, so unlike asynq's recoverer this can't act ahead of the reservation
+// timeout — it only ever sees a message once IronMQ has already made it
+// available again.
+type Recoverer struct {
+	q   *Queue
+	opt RecovererOptions
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRecoverer returns a Recoverer that recovers messages from q. Queue
+// starts one automatically the first time Consumer is called, and stops it
+// in CloseTimeout.
+func NewRecoverer(q *Queue, opt *RecovererOptions) *Recoverer {
+	if opt == nil {
+		opt = &RecovererOptions{}
+	}
+	opt.init()
+
+	return &Recoverer{
+		q:    q,
+		opt:  *opt,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Run checks q for recoverable messages every CheckInterval until Stop is
+// called. It is meant to be run in its own goroutine.
+func (rec *Recoverer) Run() {
+	defer close(rec.done)
+
+	ticker := time.NewTicker(rec.opt.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := rec.recoverOnce(); err != nil {
+				internal.Logger.Printf("ironmq: recoverer failed: %s", err)
+			}
+		case <-rec.stop:
+			return
+		}
+	}
+}
+
+// Stop stops the recoverer and waits for any in-progress check to finish.
+func (rec *Recoverer) Stop() {
+	close(rec.stop)
+	<-rec.done
+}
+
+func (rec *Recoverer) recoverOnce() error {
+	reservationSecs := int(rec.q.opt.ReservationTimeout / time.Second)
+
+	mqMsgs, err := rec.q.q.LongPoll(rec.opt.BatchSize, reservationSecs, 0, false)
+	if err != nil {
+		if v, ok := err.(api.HTTPResponseError); ok && v.StatusCode() == 404 {
+			return nil
+		}
+		return err
+	}
+
+	for _, msg := range rec.q.decodeMessages(mqMsgs) {
+		msg := msg
+
+		if rec.retriesExhausted(&msg) {
+			if err := rec.q.Archive(&msg); err != nil {
+				internal.Logger.Printf("ironmq: archiving %s failed: %s", msg.ID, err)
+			}
+			continue
+		}
+
+		msg.Delay = time.Duration(msg.ReservedCount) * time.Second
+		if err := rec.q.Release(&msg); err != nil {
+			internal.Logger.Printf("ironmq: releasing recovered message %s failed: %s", msg.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// retriesExhausted reports whether msg has been reserved at least as many
+// times as its task's RetryLimit allows, i.e. whether it belongs in
+// QueueOptions.ArchiveQueue rather than back on the queue. Falls back to
+// MaxReserveCount for a task that was never registered on this process,
+// since there's no RetryLimit to consult.
+func (rec *Recoverer) retriesExhausted(msg *taskq.Message) bool {
+	task := taskq.Tasks.Get(msg.TaskName)
+	if task == nil {
+		return msg.ReservedCount >= rec.opt.MaxReserveCount
+	}
+	return msg.ReservedCount >= task.Options().RetryLimit
+}
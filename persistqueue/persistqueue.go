@@ -0,0 +1,302 @@
+// Package persistqueue implements a taskq.Queue wrapper that buffers
+// messages to disk until the wrapped queue has durably handed them off to
+// its backend, so a process crash between Add and the inner queue's
+// background flush doesn't lose messages. It plays the same role for taskq
+// that gitea's "wrapped" queue plays on top of its channel queue: a
+// BoltDB-backed persistent queue underneath, replayed on startup.
+package persistqueue
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/vickxxx/taskq/v3"
+	"github.com/vickxxx/taskq/v3/internal"
+)
+
+var pendingBucket = []byte("pending")
+
+// Options configures a Queue.
+type Options struct {
+	// Dir is the directory the BoltDB file is stored in.
+	Dir string
+
+	// StartupTimeout bounds how long replaying unflushed messages on
+	// startup is allowed to take, so a very large backlog can't hang process
+	// boot indefinitely. The deadline is checked before each message is
+	// pushed to the inner queue; a single inner Add that blocks is not
+	// interrupted. Default is 30 seconds.
+	StartupTimeout time.Duration
+}
+
+func (opt *Options) init() {
+	if opt.StartupTimeout == 0 {
+		opt.StartupTimeout = 30 * time.Second
+	}
+}
+
+// Queue wraps an inner taskq.Queue, persisting every message Add'ed to it in
+// a local BoltDB file until Queue's own Consumer acks it with Delete, which
+// for a write-behind buffer like memqueue only happens once its handler (the
+// real backend push) has returned successfully. Queue must own the consume
+// loop itself (see Consumer) rather than delegate to inner.Consumer, since
+// the ack that matters for durability is the one made through Queue.Delete.
+type Queue struct {
+	inner    taskq.Queue
+	opt      Options
+	db       *bolt.DB
+	consumer *taskq.Consumer
+
+	seq uint64
+
+	mu sync.Mutex
+	// pending maps a message's ID to its BoltDB sequence key. Messages are
+	// keyed by ID rather than pointer: the *taskq.Message the consumer hands
+	// to Delete is decoded fresh off the inner queue and is never the same
+	// pointer Add was called with.
+	pending map[string]uint64
+}
+
+var _ taskq.Queue = (*Queue)(nil)
+
+// New opens (or creates) the BoltDB file under opt.Dir and returns a Queue
+// wrapping inner, replaying any messages left over from a previous process
+// into inner before returning.
+func New(inner taskq.Queue, opt *Options) (*Queue, error) {
+	if opt == nil {
+		opt = &Options{}
+	}
+	opt.init()
+
+	db, err := bolt.Open(fmt.Sprintf("%s/%s.db", opt.Dir, inner.Name()), 0o600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(pendingBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	q := &Queue{
+		inner:   inner,
+		opt:     *opt,
+		db:      db,
+		pending: make(map[string]uint64),
+	}
+
+	if err := q.replay(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// replay pushes every message left in the BoltDB file from a previous run
+// into the inner queue, bounded by opt.StartupTimeout. Keys are big-endian
+// sequence numbers, so a plain bucket scan visits them in the order they
+// were originally Add'ed.
+//
+// Each message is replayed in its own short transaction rather than one
+// transaction spanning the whole backlog: inner.Add can block on network
+// I/O, and holding a single bolt.Tx open across that would both serialize
+// replay behind the slowest push and, if StartupTimeout fires while a Tx is
+// still open, deadlock the db.Close() in New (Close waits for open
+// transactions to finish).
+func (q *Queue) replay() error {
+	type entry struct {
+		key []byte
+		msg *taskq.Message
+	}
+
+	var entries []entry
+	var unreadable [][]byte
+	err := q.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).ForEach(func(k, v []byte) error {
+			msg := new(taskq.Message)
+			if err := json.Unmarshal(v, msg); err != nil {
+				internal.Logger.Printf("persistqueue: dropping unreadable message %x: %s", k, err)
+				unreadable = append(unreadable, append([]byte(nil), k...))
+				return nil
+			}
+			entries = append(entries, entry{key: append([]byte(nil), k...), msg: msg})
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range unreadable {
+		if err := q.deleteKey(k); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(q.opt.StartupTimeout)
+	for _, e := range entries {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("persistqueue: replay did not finish within %s", q.opt.StartupTimeout)
+		}
+		if err := q.inner.Add(e.msg); err != nil {
+			return err
+		}
+		if err := q.deleteKey(e.key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q *Queue) deleteKey(key []byte) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).Delete(key)
+	})
+}
+
+func (q *Queue) Name() string {
+	return q.inner.Name()
+}
+
+func (q *Queue) String() string {
+	return fmt.Sprintf("persistqueue(%s)", q.inner.String())
+}
+
+func (q *Queue) Options() *taskq.QueueOptions {
+	return q.inner.Options()
+}
+
+// Consumer returns Queue's own Consumer, creating it on first call. It must
+// be bound to Queue rather than inner: a Consumer bound to inner would ack
+// through inner.Delete directly, bypassing Queue.Delete and the disk cleanup
+// it does, so on-disk entries would never be removed and every restart would
+// replay the entire backlog into inner again.
+func (q *Queue) Consumer() *taskq.Consumer {
+	if q.consumer == nil {
+		q.consumer = taskq.NewConsumer(q)
+	}
+	return q.consumer
+}
+
+func (q *Queue) Len() (int, error) {
+	return q.inner.Len()
+}
+
+// Add persists msg to disk, keyed by a monotonic sequence number, and then
+// forwards it to the inner queue. The on-disk copy is only removed once
+// Delete is called for the same message id, which Queue's own Consumer does
+// after the inner queue's handler — the actual push to the backend — has
+// succeeded. If the process crashes before that, the message is replayed
+// the next time New is called.
+func (q *Queue) Add(msg *taskq.Message) error {
+	q.mu.Lock()
+	q.seq++
+	seq := q.seq
+	q.mu.Unlock()
+	key := seqKey(seq)
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).Put(key, b)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := q.inner.Add(msg); err != nil {
+		_ = q.deleteKey(key)
+		return err
+	}
+
+	// inner.Add assigns msg.ID as a side effect (mirroring ironmq.Queue.add).
+	// Without an ID we have nothing to match against in Delete, so the
+	// on-disk copy would outlive the message and only get cleaned up by the
+	// next replay; log and move on rather than corrupt the pending map.
+	if msg.ID == "" {
+		internal.Logger.Printf("persistqueue: message got no ID from %s, cannot track its disk copy for cleanup", q.inner.Name())
+		return nil
+	}
+
+	q.mu.Lock()
+	q.pending[msg.ID] = seq
+	q.mu.Unlock()
+
+	return nil
+}
+
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+func (q *Queue) ReserveN(ctx context.Context, n int, waitTimeout time.Duration) ([]taskq.Message, error) {
+	return q.inner.ReserveN(ctx, n, waitTimeout)
+}
+
+// Peek delegates to the inner queue.
+func (q *Queue) Peek(ctx context.Context, n int) ([]taskq.Message, error) {
+	return q.inner.Peek(ctx, n)
+}
+
+func (q *Queue) Release(msg *taskq.Message) error {
+	return q.inner.Release(msg)
+}
+
+// Delete acks msg on the inner queue and, if it was still pending on disk,
+// removes its persisted copy. Queue's own Consumer (see Consumer) calls this
+// once the wrapped handler — the actual push to the backend — has returned
+// successfully, which is the durability boundary Add is meant to cover.
+func (q *Queue) Delete(msg *taskq.Message) error {
+	if err := q.inner.Delete(msg); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	seq, ok := q.pending[msg.ID]
+	if ok {
+		delete(q.pending, msg.ID)
+	}
+	q.mu.Unlock()
+
+	if ok {
+		return q.deleteKey(seqKey(seq))
+	}
+	return nil
+}
+
+func (q *Queue) Purge() error {
+	return q.inner.Purge()
+}
+
+// Close is like CloseTimeout with a 30 second timeout.
+func (q *Queue) Close() error {
+	return q.CloseTimeout(30 * time.Second)
+}
+
+func (q *Queue) CloseTimeout(timeout time.Duration) error {
+	if q.consumer != nil {
+		_ = q.consumer.StopTimeout(timeout)
+	}
+
+	firstErr := q.inner.CloseTimeout(timeout)
+	if err := q.db.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}